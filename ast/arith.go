@@ -0,0 +1,509 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type nodeKind int
+
+const (
+	nodeLit nodeKind = iota
+	nodeIdent
+	nodeUnary
+	nodeBinary
+	nodeTernary
+)
+
+type node struct {
+	kind    nodeKind
+	val     int64
+	name    string
+	op      string
+	a, b, c *node // a/b for unary(a only)/binary; a,b,c for ternary cond/then/else
+}
+
+// evalArith parses and evaluates a POSIX/bash-style arithmetic
+// expression (the text inside $((...))), resolving bare identifiers
+// through env.
+func evalArith(expr string, env func(string) (string, bool)) (int64, error) {
+	p := &arithParser{rs: []rune(expr)}
+	n, err := p.parseTernary()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.rs) {
+		return 0, fmt.Errorf("shlex: unexpected %q in arithmetic expression", string(p.rs[p.pos:]))
+	}
+	return evalNode(n, env)
+}
+
+func evalNode(n *node, env func(string) (string, bool)) (int64, error) {
+	switch n.kind {
+	case nodeLit:
+		return n.val, nil
+	case nodeIdent:
+		s, ok := env(n.name)
+		if !ok || s == "" {
+			return 0, nil
+		}
+		v, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("shlex: %s: %q is not a valid integer", n.name, s)
+		}
+		return v, nil
+	case nodeUnary:
+		v, err := evalNode(n.a, env)
+		if err != nil {
+			return 0, err
+		}
+		switch n.op {
+		case "-":
+			return -v, nil
+		case "+":
+			return v, nil
+		case "!":
+			return boolInt(v == 0), nil
+		case "~":
+			return ^v, nil
+		}
+	case nodeBinary:
+		switch n.op {
+		case "&&":
+			l, err := evalNode(n.a, env)
+			if err != nil {
+				return 0, err
+			}
+			if l == 0 {
+				return 0, nil
+			}
+			r, err := evalNode(n.b, env)
+			if err != nil {
+				return 0, err
+			}
+			return boolInt(r != 0), nil
+		case "||":
+			l, err := evalNode(n.a, env)
+			if err != nil {
+				return 0, err
+			}
+			if l != 0 {
+				return 1, nil
+			}
+			r, err := evalNode(n.b, env)
+			if err != nil {
+				return 0, err
+			}
+			return boolInt(r != 0), nil
+		default:
+			l, err := evalNode(n.a, env)
+			if err != nil {
+				return 0, err
+			}
+			r, err := evalNode(n.b, env)
+			if err != nil {
+				return 0, err
+			}
+			return evalBinaryOp(n.op, l, r)
+		}
+	case nodeTernary:
+		c, err := evalNode(n.a, env)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return evalNode(n.b, env)
+		}
+		return evalNode(n.c, env)
+	}
+	return 0, fmt.Errorf("shlex: invalid arithmetic expression")
+}
+
+func evalBinaryOp(op string, l, r int64) (int64, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, fmt.Errorf("shlex: division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return 0, fmt.Errorf("shlex: division by zero")
+		}
+		return l % r, nil
+	case "**":
+		return powInt(l, r), nil
+	case "<<":
+		return l << uint(r), nil
+	case ">>":
+		return l >> uint(r), nil
+	case "&":
+		return l & r, nil
+	case "|":
+		return l | r, nil
+	case "^":
+		return l ^ r, nil
+	case "==":
+		return boolInt(l == r), nil
+	case "!=":
+		return boolInt(l != r), nil
+	case "<":
+		return boolInt(l < r), nil
+	case "<=":
+		return boolInt(l <= r), nil
+	case ">":
+		return boolInt(l > r), nil
+	case ">=":
+		return boolInt(l >= r), nil
+	}
+	return 0, fmt.Errorf("shlex: unknown arithmetic operator %q", op)
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func powInt(base, exp int64) int64 {
+	if exp < 0 {
+		return 0
+	}
+	var result int64 = 1
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
+// arithParser is a small recursive-descent parser over the operators
+// bash's $((...)) supports, built bottom-up from POSIX's precedence
+// table (tightest first): primary, unary (! ~ - +), ** (right-assoc),
+// * / %, + -, << >>, relational, equality, &, ^, |, &&, ||, ?:.
+type arithParser struct {
+	rs  []rune
+	pos int
+}
+
+func (p *arithParser) skipSpace() {
+	for p.pos < len(p.rs) && (p.rs[p.pos] == ' ' || p.rs[p.pos] == '\t' || p.rs[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *arithParser) hasPrefix(s string) bool {
+	rs := []rune(s)
+	if p.pos+len(rs) > len(p.rs) {
+		return false
+	}
+	for i, r := range rs {
+		if p.rs[p.pos+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *arithParser) acceptOp(ops ...string) string {
+	p.skipSpace()
+	for _, op := range ops {
+		if p.hasPrefix(op) {
+			p.pos += len([]rune(op))
+			return op
+		}
+	}
+	return ""
+}
+
+// acceptSingle accepts c unless it is immediately doubled (c==c), so
+// parseBitAnd/parseBitOr don't swallow && or ||.
+func (p *arithParser) acceptSingle(c rune) bool {
+	p.skipSpace()
+	if p.pos < len(p.rs) && p.rs[p.pos] == c && !(p.pos+1 < len(p.rs) && p.rs[p.pos+1] == c) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *arithParser) parseTernary() (*node, error) {
+	cond, err := p.parseLogicalOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.acceptOp("?") == "" {
+		return cond, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.acceptOp(":") == "" {
+		return nil, fmt.Errorf("shlex: missing : in ?: expression")
+	}
+	els, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: nodeTernary, a: cond, b: then, c: els}, nil
+}
+
+func (p *arithParser) parseLogicalOr() (*node, error) {
+	left, err := p.parseLogicalAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") != "" {
+		right, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: "||", a: left, b: right}
+	}
+	return left, nil
+}
+
+func (p *arithParser) parseLogicalAnd() (*node, error) {
+	left, err := p.parseBitOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") != "" {
+		right, err := p.parseBitOr()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: "&&", a: left, b: right}
+	}
+	return left, nil
+}
+
+func (p *arithParser) parseBitOr() (*node, error) {
+	left, err := p.parseBitXor()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptSingle('|') {
+		right, err := p.parseBitXor()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: "|", a: left, b: right}
+	}
+	return left, nil
+}
+
+func (p *arithParser) parseBitXor() (*node, error) {
+	left, err := p.parseBitAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptSingle('^') {
+		right, err := p.parseBitAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: "^", a: left, b: right}
+	}
+	return left, nil
+}
+
+func (p *arithParser) parseBitAnd() (*node, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptSingle('&') {
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: "&", a: left, b: right}
+	}
+	return left, nil
+}
+
+func (p *arithParser) parseEquality() (*node, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.acceptOp("==", "!=")
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: op, a: left, b: right}
+	}
+}
+
+func (p *arithParser) parseRelational() (*node, error) {
+	left, err := p.parseShift()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.acceptOp("<=", ">=", "<", ">")
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.parseShift()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: op, a: left, b: right}
+	}
+}
+
+func (p *arithParser) parseShift() (*node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.acceptOp("<<", ">>")
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: op, a: left, b: right}
+	}
+}
+
+func (p *arithParser) parseAdditive() (*node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.acceptOp("+", "-")
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: op, a: left, b: right}
+	}
+}
+
+func (p *arithParser) parseMultiplicative() (*node, error) {
+	left, err := p.parsePower()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.acceptOp("*", "/", "%")
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.parsePower()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeBinary, op: op, a: left, b: right}
+	}
+}
+
+func (p *arithParser) parsePower() (*node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if p.acceptOp("**") == "" {
+		return left, nil
+	}
+	right, err := p.parsePower()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: nodeBinary, op: "**", a: left, b: right}, nil
+}
+
+func (p *arithParser) parseUnary() (*node, error) {
+	if op := p.acceptOp("!", "~", "-", "+"); op != "" {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeUnary, op: op, a: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *arithParser) parsePrimary() (*node, error) {
+	p.skipSpace()
+	if p.pos >= len(p.rs) {
+		return nil, fmt.Errorf("shlex: unexpected end of arithmetic expression")
+	}
+	switch r := p.rs[p.pos]; {
+	case r == '(':
+		p.pos++
+		n, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.rs) || p.rs[p.pos] != ')' {
+			return nil, fmt.Errorf("shlex: missing ) in arithmetic expression")
+		}
+		p.pos++
+		return n, nil
+	case isDigit(r):
+		return p.parseNumber()
+	case isNameStart(r):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.rs) && isNameRune(p.rs[p.pos]) {
+			p.pos++
+		}
+		return &node{kind: nodeIdent, name: string(p.rs[start:p.pos])}, nil
+	default:
+		return nil, fmt.Errorf("shlex: unexpected %q in arithmetic expression", r)
+	}
+}
+
+func (p *arithParser) parseNumber() (*node, error) {
+	start := p.pos
+	hex := p.hasPrefix("0x") || p.hasPrefix("0X")
+	if hex {
+		p.pos += 2
+	}
+	for p.pos < len(p.rs) && (isDigit(p.rs[p.pos]) || (hex && isHexLetter(p.rs[p.pos]))) {
+		p.pos++
+	}
+	lit := string(p.rs[start:p.pos])
+	v, err := strconv.ParseInt(lit, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("shlex: %q is not a valid integer", lit)
+	}
+	return &node{kind: nodeLit, val: v}, nil
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexLetter(r rune) bool {
+	return (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
@@ -0,0 +1,322 @@
+// Package ast turns the token stream produced by shlex.Scanner into a
+// POSIX-shell command tree: pipelines, and-or lists, redirections and
+// assignments, with each Word keeping its quoting/expansion segments
+// intact. It does not implement a shell; it is the front-end other
+// tools can build one, or any shell-aware tooling, on top of.
+package ast
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/midbel/shlex"
+)
+
+// Op joins one Pipeline to the ones before it in a List.
+type Op int
+
+const (
+	// FIRST marks the first Pipeline of a new and-or list.
+	FIRST Op = iota
+	AND
+	OR
+)
+
+// List is a sequence of and-or list entries, in the order they were
+// written. Consecutive entries with Op AND/OR belong to the same
+// "cmd1 && cmd2 || cmd3" chain; an entry with Op FIRST starts a new one.
+type List []*AndOr
+
+// AndOr is one Pipeline together with how it is joined to the entry
+// before it.
+type AndOr struct {
+	Op       Op
+	Pipeline Pipeline
+}
+
+// Pipeline is one or more Commands connected by | or |&.
+type Pipeline []Command
+
+// Command is a single simple command: variable assignments, the words
+// that make up its name and arguments, and its redirections.
+type Command struct {
+	Assignments []Assignment
+	Args        []Word
+	Redirs      []Redirection
+	// PipeBoth is true when this Command is joined to the next stage
+	// of its Pipeline with |& instead of |, i.e. its stderr is piped
+	// along with its stdout.
+	PipeBoth bool
+}
+
+// Assignment is a NAME=value word preceding a command, e.g. FOO=bar.
+type Assignment struct {
+	Name  string
+	Value Word
+}
+
+// RedirOp identifies a redirection operator.
+type RedirOp int
+
+const (
+	RedirIn      RedirOp = iota // <
+	RedirOut                    // >
+	RedirAppend                 // >>
+	RedirHeredoc                // <<
+	RedirDupIn                  // <&
+	RedirDupOut                 // >&
+)
+
+// Redirection is one <, >, >>, <<, <& or >& clause attached to a
+// Command.
+type Redirection struct {
+	IoNumber int
+	Op       RedirOp
+	Target   Word
+}
+
+// redirOps maps the Operator literals the Scanner produces for <, <<,
+// <&, >, >> and >& to the RedirOp they denote.
+var redirOps = map[string]RedirOp{
+	"<":  RedirIn,
+	"<<": RedirHeredoc,
+	"<&": RedirDupIn,
+	">":  RedirOut,
+	">>": RedirAppend,
+	">&": RedirDupOut,
+}
+
+// defaultIoNumber is the file descriptor a redirection operator applies
+// to when it isn't preceded by an explicit IO number, e.g. the 1 in
+// "cmd > out" or the 0 in "cmd < in".
+func defaultIoNumber(op RedirOp) int {
+	if op == RedirOut || op == RedirAppend || op == RedirDupOut {
+		return 1
+	}
+	return 0
+}
+
+// isIoNumber reports whether lit is the digit sequence that may
+// immediately precede a redirection operator, e.g. the "2" in "2>&1".
+func isIoNumber(lit string) bool {
+	if lit == "" {
+		return false
+	}
+	for _, r := range lit {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseString parses str the same way Parse does.
+func ParseString(str string) (List, error) {
+	return Parse(strings.NewReader(str))
+}
+
+// Parse reads every command off r and returns the List it forms.
+func Parse(r io.Reader) (List, error) {
+	p := &parser{sc: shlex.NewScanner(r)}
+	p.next()
+	list := p.parseList()
+	return list, p.err
+}
+
+type parser struct {
+	sc    *shlex.Scanner
+	tok   shlex.Token
+	atEOF bool
+	err   error
+}
+
+func (p *parser) next() {
+	if p.atEOF {
+		return
+	}
+	tok, err := p.sc.Next()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			p.err = err
+		}
+		p.atEOF = true
+		return
+	}
+	p.tok = tok
+}
+
+func (p *parser) skipNewlines() {
+	for !p.atEOF && p.tok.Kind == shlex.Newline {
+		p.next()
+	}
+}
+
+func (p *parser) parseList() List {
+	var list List
+	p.skipNewlines()
+outer:
+	for !p.atEOF {
+		op := FIRST
+		for {
+			pipe := p.parsePipeline()
+			list = append(list, &AndOr{Op: op, Pipeline: pipe})
+			if p.atEOF {
+				break outer
+			}
+			switch {
+			case p.isOp("&&"):
+				op = AND
+				p.next()
+				p.skipNewlines()
+			case p.isOp("||"):
+				op = OR
+				p.next()
+				p.skipNewlines()
+			case p.isOp(";"), p.isOp("&"):
+				p.next()
+				p.skipNewlines()
+				continue outer
+			case p.tok.Kind == shlex.Newline:
+				p.skipNewlines()
+				continue outer
+			default:
+				break outer
+			}
+		}
+	}
+	return list
+}
+
+func (p *parser) isOp(lit string) bool {
+	return !p.atEOF && p.tok.Kind == shlex.Operator && p.tok.Literal == lit
+}
+
+func (p *parser) parsePipeline() Pipeline {
+	var pipe Pipeline
+	for {
+		cmd := p.parseCommand()
+		both := p.isOp("|&")
+		pipe = append(pipe, cmd)
+		if !p.isOp("|") && !both {
+			break
+		}
+		pipe[len(pipe)-1].PipeBoth = both
+		p.next()
+	}
+	return pipe
+}
+
+func (p *parser) parseCommand() Command {
+	var (
+		cmd     Command
+		started bool
+	)
+	for !p.atEOF {
+		if op, ok := redirOps[p.tok.Literal]; ok && p.tok.Kind == shlex.Operator {
+			p.next()
+			target, _ := p.parseWord()
+			cmd.Redirs = append(cmd.Redirs, Redirection{IoNumber: defaultIoNumber(op), Op: op, Target: target})
+			continue
+		}
+		if !isWordStart(p.tok) {
+			break
+		}
+		tok := p.tok
+		end := tok.Pos.Offset + runeLen(tok.Literal)
+
+		if tok.Kind == shlex.Word && isIoNumber(tok.Literal) {
+			p.next()
+			if !p.atEOF && p.tok.Kind == shlex.Operator && p.tok.Pos.Offset == end {
+				if op, ok := redirOps[p.tok.Literal]; ok {
+					ioNumber, _ := strconv.Atoi(tok.Literal)
+					p.next()
+					target, _ := p.parseWord()
+					cmd.Redirs = append(cmd.Redirs, Redirection{IoNumber: ioNumber, Op: op, Target: target})
+					continue
+				}
+			}
+			cmd.Args = append(cmd.Args, p.mergeWord(tokenSegments(tok), end))
+			started = true
+			continue
+		}
+
+		if tok.Kind == shlex.Word && !started && namePattern.MatchString(tok.Literal) {
+			p.next()
+			if !p.atEOF && p.tok.Kind == shlex.Word && p.tok.Pos.Offset == end && strings.HasPrefix(p.tok.Literal, "=") {
+				val := p.tok
+				rest := strings.TrimPrefix(val.Literal, "=")
+				valEnd := val.Pos.Offset + runeLen(val.Literal)
+				p.next()
+				value := p.mergeWord(splitWordSegments(rest), valEnd)
+				cmd.Assignments = append(cmd.Assignments, Assignment{Name: tok.Literal, Value: value})
+				continue
+			}
+			cmd.Args = append(cmd.Args, p.mergeWord(tokenSegments(tok), end))
+			started = true
+			continue
+		}
+
+		w, _ := p.parseWord()
+		cmd.Args = append(cmd.Args, w)
+		started = true
+	}
+	return cmd
+}
+
+func isWordStart(tok shlex.Token) bool {
+	switch tok.Kind {
+	case shlex.Word, shlex.SingleQuoted, shlex.DoubleQuoted, shlex.CommandSubst, shlex.Arithmetic, shlex.Brace:
+		return true
+	default:
+		return false
+	}
+}
+
+func tokenSegments(tok shlex.Token) []Segment {
+	switch tok.Kind {
+	case shlex.Word:
+		return splitWordSegments(tok.Literal)
+	case shlex.SingleQuoted:
+		return []Segment{{Kind: SingleQuoted, Value: tok.Literal}}
+	case shlex.DoubleQuoted:
+		return []Segment{{Kind: DoubleQuoted, Value: tok.Literal}}
+	case shlex.CommandSubst:
+		return []Segment{{Kind: CommandSubst, Value: tok.Literal}}
+	case shlex.Arithmetic:
+		return []Segment{{Kind: Arithmetic, Value: tok.Literal}}
+	case shlex.Brace:
+		return []Segment{{Kind: Brace, Value: tok.Literal}}
+	default:
+		return nil
+	}
+}
+
+// parseWord consumes one logical shell word: the current token plus
+// every token directly following it with nothing (not even a blank)
+// in between, the way foo"bar"$baz scans as three tokens that form a
+// single word.
+func (p *parser) parseWord() (Word, bool) {
+	if p.atEOF || !isWordStart(p.tok) {
+		return Word{}, false
+	}
+	return p.mergeWord(nil, -1), true
+}
+
+func (p *parser) mergeWord(segs []Segment, endOffset int) Word {
+	for !p.atEOF && isWordStart(p.tok) && (endOffset < 0 || p.tok.Pos.Offset == endOffset) {
+		segs = append(segs, tokenSegments(p.tok)...)
+		endOffset = p.tok.Pos.Offset + runeLen(p.tok.Literal)
+		p.next()
+	}
+	return Word{Segments: segs}
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,200 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/midbel/shlex/ast"
+)
+
+func words(cmd ast.Command) []string {
+	out := make([]string, len(cmd.Args))
+	for i, w := range cmd.Args {
+		out[i] = w.String()
+	}
+	return out
+}
+
+func mustParse(t *testing.T, input string) ast.List {
+	t.Helper()
+	list, err := ast.ParseString(input)
+	if err != nil {
+		t.Fatalf("%s: unexpected error! %s", input, err)
+	}
+	return list
+}
+
+func TestParseSimpleCommand(t *testing.T) {
+	list := mustParse(t, "echo foo bar")
+	if len(list) != 1 {
+		t.Fatalf("expected 1 and-or entry, got %d", len(list))
+	}
+	pipe := list[0].Pipeline
+	if len(pipe) != 1 {
+		t.Fatalf("expected 1 command in pipeline, got %d", len(pipe))
+	}
+	got := words(pipe[0])
+	want := []string{"echo", "foo", "bar"}
+	if len(got) != len(want) {
+		t.Fatalf("args mismatched! got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("arg %d mismatched! got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseWordGluesAdjacentSegments(t *testing.T) {
+	list := mustParse(t, `echo foo"bar"`)
+	args := words(list[0].Pipeline[0])
+	if len(args) != 2 {
+		t.Fatalf("expected 2 args, got %v", args)
+	}
+	if args[1] != `foobar` {
+		t.Fatalf("expected adjacent tokens to glue into one word, got %q", args[1])
+	}
+}
+
+func TestParsePipeline(t *testing.T) {
+	list := mustParse(t, "echo foo | cat |& cut")
+	pipe := list[0].Pipeline
+	if len(pipe) != 3 {
+		t.Fatalf("expected 3 commands in pipeline, got %d", len(pipe))
+	}
+	if pipe[0].PipeBoth {
+		t.Fatalf("first command should not pipe stderr")
+	}
+	if !pipe[1].PipeBoth {
+		t.Fatalf("second command should pipe stderr along with stdout")
+	}
+}
+
+func TestParseAndOrList(t *testing.T) {
+	list := mustParse(t, "cmd1 && cmd2 || cmd3")
+	if len(list) != 3 {
+		t.Fatalf("expected 3 and-or entries, got %d", len(list))
+	}
+	ops := []ast.Op{list[0].Op, list[1].Op, list[2].Op}
+	want := []ast.Op{ast.FIRST, ast.AND, ast.OR}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("op %d mismatched! got %v, want %v", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestParseSemicolonSeparatesCommands(t *testing.T) {
+	list := mustParse(t, "echo a; echo b")
+	if len(list) != 2 {
+		t.Fatalf("expected 2 and-or entries, got %d", len(list))
+	}
+}
+
+func TestParseNewlineSeparatesCommandsEvenWithTrailingBlank(t *testing.T) {
+	list := mustParse(t, "echo a \necho b")
+	if len(list) != 2 {
+		t.Fatalf("expected 2 and-or entries, got %d", len(list))
+	}
+}
+
+func TestParseAssignment(t *testing.T) {
+	list := mustParse(t, "FOO=bar echo ok")
+	cmd := list[0].Pipeline[0]
+	if len(cmd.Assignments) != 1 {
+		t.Fatalf("expected 1 assignment, got %d", len(cmd.Assignments))
+	}
+	a := cmd.Assignments[0]
+	if a.Name != "FOO" || a.Value.String() != "bar" {
+		t.Fatalf("assignment mismatched! got %s=%s", a.Name, a.Value.String())
+	}
+	if got := words(cmd); len(got) != 2 || got[0] != "echo" || got[1] != "ok" {
+		t.Fatalf("args mismatched! got %v", got)
+	}
+}
+
+func TestParseRedirection(t *testing.T) {
+	list := mustParse(t, "cmd > out.txt 2> err.txt")
+	cmd := list[0].Pipeline[0]
+	if len(cmd.Redirs) != 2 {
+		t.Fatalf("expected 2 redirections, got %d", len(cmd.Redirs))
+	}
+	first := cmd.Redirs[0]
+	if first.IoNumber != 1 || first.Op != ast.RedirOut || first.Target.String() != "out.txt" {
+		t.Fatalf("first redirection mismatched! %+v", first)
+	}
+	second := cmd.Redirs[1]
+	if second.IoNumber != 2 || second.Op != ast.RedirOut || second.Target.String() != "err.txt" {
+		t.Fatalf("second redirection mismatched! %+v", second)
+	}
+}
+
+func TestParseDupRedirection(t *testing.T) {
+	list := mustParse(t, "cmd 2>&1")
+	if len(list) != 1 {
+		t.Fatalf("expected 1 and-or entry, got %d", len(list))
+	}
+	cmd := list[0].Pipeline[0]
+	if got := words(cmd); len(got) != 1 || got[0] != "cmd" {
+		t.Fatalf("args mismatched! got %v", got)
+	}
+	if len(cmd.Redirs) != 1 {
+		t.Fatalf("expected 1 redirection, got %d", len(cmd.Redirs))
+	}
+	r := cmd.Redirs[0]
+	if r.IoNumber != 2 || r.Op != ast.RedirDupOut || r.Target.String() != "1" {
+		t.Fatalf("redirection mismatched! %+v", r)
+	}
+}
+
+func TestParseDupRedirectionWithoutExplicitIoNumber(t *testing.T) {
+	list := mustParse(t, "cmd >&2")
+	cmd := list[0].Pipeline[0]
+	if len(cmd.Redirs) != 1 {
+		t.Fatalf("expected 1 redirection, got %d", len(cmd.Redirs))
+	}
+	r := cmd.Redirs[0]
+	if r.IoNumber != 1 || r.Op != ast.RedirDupOut || r.Target.String() != "2" {
+		t.Fatalf("redirection mismatched! %+v", r)
+	}
+}
+
+func TestParseRedirectionWithoutSurroundingBlanks(t *testing.T) {
+	tests := []struct {
+		input  string
+		arg    string
+		ioNum  int
+		op     ast.RedirOp
+		target string
+	}{
+		{"cat<file", "cat", 0, ast.RedirIn, "file"},
+		{"cat>out.txt", "cat", 1, ast.RedirOut, "out.txt"},
+	}
+	for _, tt := range tests {
+		list := mustParse(t, tt.input)
+		cmd := list[0].Pipeline[0]
+		if got := words(cmd); len(got) != 1 || got[0] != tt.arg {
+			t.Fatalf("%s: args mismatched! got %v", tt.input, got)
+		}
+		if len(cmd.Redirs) != 1 {
+			t.Fatalf("%s: expected 1 redirection, got %d", tt.input, len(cmd.Redirs))
+		}
+		r := cmd.Redirs[0]
+		if r.IoNumber != tt.ioNum || r.Op != tt.op || r.Target.String() != tt.target {
+			t.Fatalf("%s: redirection mismatched! %+v", tt.input, r)
+		}
+	}
+}
+
+func TestParseHeredocAndAppendOperators(t *testing.T) {
+	list := mustParse(t, "cmd << EOF")
+	cmd := list[0].Pipeline[0]
+	if len(cmd.Redirs) != 1 || cmd.Redirs[0].Op != ast.RedirHeredoc || cmd.Redirs[0].Target.String() != "EOF" {
+		t.Fatalf("heredoc redirection mismatched! %+v", cmd.Redirs)
+	}
+
+	list = mustParse(t, "cmd >> out.txt")
+	cmd = list[0].Pipeline[0]
+	if len(cmd.Redirs) != 1 || cmd.Redirs[0].Op != ast.RedirAppend || cmd.Redirs[0].Target.String() != "out.txt" {
+		t.Fatalf("append redirection mismatched! %+v", cmd.Redirs)
+	}
+}
@@ -0,0 +1,288 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Evaluator expands a Word into the argv strings a shell would produce
+// from it: resolving variables and parameter expansions, running
+// command substitutions and arithmetic expressions, and splitting
+// unquoted results on IFS the way a shell splits an unquoted expansion
+// into possibly several words.
+type Evaluator struct {
+	// Env looks up a shell variable by name. The bool result tells a
+	// missing variable from one set to the empty string; the :- := :?
+	// :+ operators treat both alike, as a shell does.
+	Env func(name string) (string, bool)
+	// Exec runs a command substitution's command line and returns its
+	// captured output. Expand trims a single trailing newline from it,
+	// matching shell behavior.
+	Exec func(command string) (string, error)
+	// IFS overrides the characters unquoted expansions split on. The
+	// zero value uses " \t\n", the shell default.
+	IFS string
+}
+
+func (e *Evaluator) ifs() string {
+	if e.IFS != "" {
+		return e.IFS
+	}
+	return " \t\n"
+}
+
+func (e *Evaluator) env(name string) (string, bool) {
+	if e.Env == nil {
+		return "", false
+	}
+	return e.Env(name)
+}
+
+func (e *Evaluator) exec(command string) (string, error) {
+	if e.Exec == nil {
+		return "", nil
+	}
+	out, err := e.Exec(command)
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+// Expand resolves every Segment of w, in order, and splits the
+// unquoted parts on IFS, returning the resulting fields. A Word made up
+// only of quoted segments always yields at least one field, even an
+// empty one, matching how a quoted "" is still an argument; a Word with
+// no segments, or whose only unquoted content disappears entirely into
+// IFS whitespace, yields none.
+func (e *Evaluator) Expand(w Word) ([]string, error) {
+	var f fielder
+	for _, seg := range w.Segments {
+		if err := e.expandSegment(seg, &f); err != nil {
+			return nil, err
+		}
+	}
+	return f.result(), nil
+}
+
+func (e *Evaluator) expandSegment(seg Segment, f *fielder) error {
+	switch seg.Kind {
+	case Literal, Brace:
+		f.addUnquoted(seg.Value, e.ifs())
+	case SingleQuoted:
+		f.addQuoted(seg.Value)
+	case DoubleQuoted:
+		return e.expandQuoted(seg.Value, f)
+	case Variable:
+		val, _ := e.env(seg.Value[1:])
+		f.addUnquoted(val, e.ifs())
+	case Parameter:
+		val, err := e.expandParameter(seg.Value)
+		if err != nil {
+			return err
+		}
+		f.addUnquoted(val, e.ifs())
+	case CommandSubst:
+		out, err := e.exec(strings.TrimSuffix(strings.TrimPrefix(seg.Value, "$("), ")"))
+		if err != nil {
+			return err
+		}
+		f.addUnquoted(out, e.ifs())
+	case Arithmetic:
+		v, err := e.evalArithmetic(seg.Value)
+		if err != nil {
+			return err
+		}
+		f.addUnquoted(strconv.FormatInt(v, 10), e.ifs())
+	}
+	return nil
+}
+
+// expandQuoted resolves the $var, ${...}, $(...) and $((...)) forms a
+// double-quoted segment's text may still contain, feeding every result
+// to f as quoted content: nothing inside double quotes is ever split on
+// IFS.
+func (e *Evaluator) expandQuoted(s string, f *fielder) error {
+	for _, seg := range splitWordSegments(s) {
+		switch seg.Kind {
+		case Literal, Brace:
+			f.addQuoted(seg.Value)
+		case Variable:
+			val, _ := e.env(seg.Value[1:])
+			f.addQuoted(val)
+		case Parameter:
+			val, err := e.expandParameter(seg.Value)
+			if err != nil {
+				return err
+			}
+			f.addQuoted(val)
+		case CommandSubst:
+			out, err := e.exec(strings.TrimSuffix(strings.TrimPrefix(seg.Value, "$("), ")"))
+			if err != nil {
+				return err
+			}
+			f.addQuoted(out)
+		case Arithmetic:
+			v, err := e.evalArithmetic(seg.Value)
+			if err != nil {
+				return err
+			}
+			f.addQuoted(strconv.FormatInt(v, 10))
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) evalArithmetic(raw string) (int64, error) {
+	expr := strings.TrimSuffix(strings.TrimPrefix(raw, "$(("), "))")
+	return evalArith(expr, e.env)
+}
+
+// expandParameter resolves a ${...} segment's inner text: plain
+// ${name}, the length form ${#name}, the :- := :? :+ default/assign/
+// error/alternative operators, the # ## % %% glob-trim operators and
+// the / // substring-replace operators.
+func (e *Evaluator) expandParameter(raw string) (string, error) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(raw, "${"), "}")
+	if strings.HasPrefix(inner, "#") && len(inner) > 1 {
+		val, _ := e.env(inner[1:])
+		return strconv.Itoa(utf8.RuneCountInString(val)), nil
+	}
+	name, op, arg := splitParameterOp(inner)
+	val, ok := e.env(name)
+	set := ok && val != ""
+	switch op {
+	case "":
+		return val, nil
+	case ":-":
+		if set {
+			return val, nil
+		}
+		return arg, nil
+	case ":=":
+		// A real shell also assigns name=arg here; Evaluator has no way
+		// to write back through Env, so it only returns the default.
+		if set {
+			return val, nil
+		}
+		return arg, nil
+	case ":?":
+		if set {
+			return val, nil
+		}
+		if arg == "" {
+			arg = "parameter null or not set"
+		}
+		return "", fmt.Errorf("shlex: %s: %s", name, arg)
+	case ":+":
+		if set {
+			return arg, nil
+		}
+		return "", nil
+	case "#", "##":
+		return trimPrefixGlob(val, arg, op == "##"), nil
+	case "%", "%%":
+		return trimSuffixGlob(val, arg, op == "%%"), nil
+	case "/", "//":
+		return splitReplace(val, arg, op == "//"), nil
+	default:
+		return val, nil
+	}
+}
+
+// splitParameterOp splits a ${...} segment's inner text into the
+// variable name, the operator that follows it (if any), and that
+// operator's argument.
+func splitParameterOp(inner string) (name, op, arg string) {
+	rs := []rune(inner)
+	i := 0
+	if i < len(rs) && isNameStart(rs[i]) {
+		i++
+		for i < len(rs) && isNameRune(rs[i]) {
+			i++
+		}
+	}
+	name = string(rs[:i])
+	rest := string(rs[i:])
+	for _, candidate := range []string{":-", ":=", ":?", ":+", "##", "#", "%%", "%", "//", "/"} {
+		if strings.HasPrefix(rest, candidate) {
+			return name, candidate, strings.TrimPrefix(rest, candidate)
+		}
+	}
+	return name, "", rest
+}
+
+// splitReplace implements ${name/pattern/repl} and ${name//pattern/repl}:
+// arg is "pattern/repl" (repl may be absent, meaning delete the match).
+func splitReplace(val, arg string, global bool) string {
+	pattern, repl, _ := strings.Cut(arg, "/")
+	if pattern == "" {
+		return val
+	}
+	re := globToRegexp(pattern)
+	if global {
+		return re.ReplaceAllString(val, strings.ReplaceAll(repl, "$", "$$"))
+	}
+	loc := re.FindStringIndex(val)
+	if loc == nil {
+		return val
+	}
+	return val[:loc[0]] + repl + val[loc[1]:]
+}
+
+// fielder accumulates the fields an expansion splits into: quoted
+// content is always appended to the current field, unquoted content is
+// split on IFS, and a field is only ever emitted for content that
+// actually reached it (so leading/trailing/collapsed IFS runs don't
+// produce empty fields, while an explicitly quoted "" still does).
+type fielder struct {
+	fields  []string
+	cur     strings.Builder
+	started bool
+}
+
+func (f *fielder) addQuoted(s string) {
+	f.cur.WriteString(s)
+	f.started = true
+}
+
+func (f *fielder) addUnquoted(s, ifs string) {
+	isSep := func(r rune) bool { return strings.ContainsRune(ifs, r) }
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if isSep(r) {
+			f.flush()
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !isSep(r) {
+					break
+				}
+				i += size
+			}
+			continue
+		}
+		start := i
+		for i < len(s) {
+			r, size = utf8.DecodeRuneInString(s[i:])
+			if isSep(r) {
+				break
+			}
+			i += size
+		}
+		f.cur.WriteString(s[start:i])
+		f.started = true
+	}
+}
+
+func (f *fielder) flush() {
+	if f.started || f.cur.Len() > 0 {
+		f.fields = append(f.fields, f.cur.String())
+		f.cur.Reset()
+		f.started = false
+	}
+}
+
+func (f *fielder) result() []string {
+	f.flush()
+	return f.fields
+}
@@ -0,0 +1,173 @@
+package ast_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/midbel/shlex/ast"
+)
+
+func argWord(t *testing.T, input string, index int) ast.Word {
+	t.Helper()
+	list := mustParse(t, input)
+	args := list[0].Pipeline[0].Args
+	if index >= len(args) {
+		t.Fatalf("%s: only %d args, wanted index %d", input, len(args), index)
+	}
+	return args[index]
+}
+
+func TestEvaluatorExpandVariable(t *testing.T) {
+	env := func(name string) (string, bool) {
+		if name == "var" {
+			return "hello", true
+		}
+		return "", false
+	}
+	e := &ast.Evaluator{Env: env}
+	got, err := e.Expand(argWord(t, "echo $var", 1))
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if len(got) != 1 || got[0] != "hello" {
+		t.Fatalf("got %q, want [hello]", got)
+	}
+}
+
+func TestEvaluatorParameterOperators(t *testing.T) {
+	set := map[string]string{"name": "bob", "empty": ""}
+	env := func(n string) (string, bool) {
+		v, ok := set[n]
+		return v, ok
+	}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{`echo ${missing:-default}`, []string{"default"}},
+		{`echo ${name:-default}`, []string{"bob"}},
+		{`echo ${empty:-default}`, []string{"default"}},
+		{`echo ${missing:+alt}`, nil},
+		{`echo ${name:+alt}`, []string{"alt"}},
+		{`echo ${name#b}`, []string{"ob"}},
+		{`echo ${name%b}`, []string{"bo"}},
+		{`echo ${#name}`, []string{"3"}},
+	}
+	for _, tt := range tests {
+		e := &ast.Evaluator{Env: env}
+		got, err := e.Expand(argWord(t, tt.input, 1))
+		if err != nil {
+			t.Fatalf("%s: unexpected error! %s", tt.input, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.input, got, tt.want)
+			continue
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %q, want %q", tt.input, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestEvaluatorParameterRequiredErrors(t *testing.T) {
+	env := func(string) (string, bool) { return "", false }
+	e := &ast.Evaluator{Env: env}
+	_, err := e.Expand(argWord(t, `echo ${missing:?not set}`, 1))
+	if err == nil {
+		t.Fatalf("expected an error for an unset required variable")
+	}
+}
+
+func TestEvaluatorParameterSubstringReplace(t *testing.T) {
+	env := func(n string) (string, bool) {
+		if n == "path" {
+			return "a/b/a", true
+		}
+		return "", false
+	}
+	e := &ast.Evaluator{Env: env}
+	got, err := e.Expand(argWord(t, `echo ${path/a/X}`, 1))
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if len(got) != 1 || got[0] != "X/b/a" {
+		t.Fatalf("got %q, want [X/b/a]", got)
+	}
+	got, err = e.Expand(argWord(t, `echo ${path//a/X}`, 1))
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if len(got) != 1 || got[0] != "X/b/X" {
+		t.Fatalf("got %q, want [X/b/X]", got)
+	}
+}
+
+func TestEvaluatorArithmetic(t *testing.T) {
+	e := &ast.Evaluator{}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"echo $((1+2*3))", "7"},
+		{"echo $((2**10))", "1024"},
+		{"echo $((1<2?10:20))", "10"},
+		{"echo $((1 && 0))", "0"},
+	}
+	for _, tt := range tests {
+		got, err := e.Expand(argWord(t, tt.input, 1))
+		if err != nil {
+			t.Fatalf("%s: unexpected error! %s", tt.input, err)
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: got %q, want [%s]", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluatorCommandSubst(t *testing.T) {
+	exec := func(cmd string) (string, error) {
+		return fmt.Sprintf("ran:%s\n", cmd), nil
+	}
+	e := &ast.Evaluator{Exec: exec}
+	got, err := e.Expand(argWord(t, "echo $(date)", 1))
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if len(got) != 1 || got[0] != "ran:date" {
+		t.Fatalf("got %q, want [ran:date]", got)
+	}
+}
+
+func TestEvaluatorSplitsUnquotedOnIFSButNotQuoted(t *testing.T) {
+	env := func(n string) (string, bool) {
+		if n == "list" {
+			return "a b  c", true
+		}
+		return "", false
+	}
+	e := &ast.Evaluator{Env: env}
+
+	got, err := e.Expand(argWord(t, "echo $list", 1))
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d mismatched! got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	got, err = e.Expand(argWord(t, `echo "$list"`, 1))
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if len(got) != 1 || got[0] != "a b  c" {
+		t.Fatalf("quoted expansion split on IFS! got %q", got)
+	}
+}
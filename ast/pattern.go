@@ -0,0 +1,101 @@
+package ast
+
+import (
+	"regexp"
+	"strings"
+)
+
+// globMatch reports whether s matches the shell glob pattern, which may
+// use * (any run, including empty) and ? (any single rune). It is
+// deliberately small: parameter expansion's #, ##, %, %% operators are
+// the only callers, and they need nothing fancier.
+func globMatch(pattern, s string) bool {
+	return globMatchRunes([]rune(pattern), []rune(s))
+}
+
+func globMatchRunes(p, s []rune) bool {
+	if len(p) == 0 {
+		return len(s) == 0
+	}
+	switch p[0] {
+	case '*':
+		for i := 0; i <= len(s); i++ {
+			if globMatchRunes(p[1:], s[i:]) {
+				return true
+			}
+		}
+		return false
+	case '?':
+		if len(s) == 0 {
+			return false
+		}
+		return globMatchRunes(p[1:], s[1:])
+	default:
+		if len(s) == 0 || s[0] != p[0] {
+			return false
+		}
+		return globMatchRunes(p[1:], s[1:])
+	}
+}
+
+// trimPrefixGlob removes the shortest (or, if longest is true, the
+// longest) prefix of value matching pattern, implementing ${var#pat}
+// and ${var##pat}.
+func trimPrefixGlob(value, pattern string, longest bool) string {
+	rs := []rune(value)
+	if longest {
+		for i := len(rs); i >= 0; i-- {
+			if globMatch(pattern, string(rs[:i])) {
+				return string(rs[i:])
+			}
+		}
+	} else {
+		for i := 0; i <= len(rs); i++ {
+			if globMatch(pattern, string(rs[:i])) {
+				return string(rs[i:])
+			}
+		}
+	}
+	return value
+}
+
+// globToRegexp compiles a shell glob pattern (only * and ? are
+// meaningful, matching globMatch's own scope) into a regexp usable to
+// find or replace a matching substring anywhere in a string, which is
+// what ${var/pattern/repl} needs and trimPrefixGlob/trimSuffixGlob
+// don't: those only ever match at one end of the value.
+func globToRegexp(pattern string) *regexp.Regexp {
+	var buf strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return regexp.MustCompile(buf.String())
+}
+
+// trimSuffixGlob removes the shortest (or, if longest is true, the
+// longest) suffix of value matching pattern, implementing ${var%pat}
+// and ${var%%pat}.
+func trimSuffixGlob(value, pattern string, longest bool) string {
+	rs := []rune(value)
+	if longest {
+		for i := 0; i <= len(rs); i++ {
+			if globMatch(pattern, string(rs[i:])) {
+				return string(rs[:i])
+			}
+		}
+	} else {
+		for i := len(rs); i >= 0; i-- {
+			if globMatch(pattern, string(rs[i:])) {
+				return string(rs[:i])
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,135 @@
+package ast
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// SegmentKind identifies what a Segment of a Word represents.
+type SegmentKind int
+
+const (
+	Literal SegmentKind = iota
+	SingleQuoted
+	DoubleQuoted
+	Variable
+	Parameter
+	CommandSubst
+	Arithmetic
+	Brace
+)
+
+// Segment is one piece of a Word. Splitting a Word into Segments keeps
+// quoting and expansion boundaries intact, so a caller can perform its
+// own expansion with full fidelity instead of working off the
+// flattened string shlex.Split produces.
+type Segment struct {
+	Kind  SegmentKind
+	Value string
+}
+
+// Word preserves the segments that make up a single shell word, in the
+// order they were written, e.g. foo"bar"$baz is three segments: a
+// Literal, a DoubleQuoted and a Variable.
+type Word struct {
+	Segments []Segment
+}
+
+// String concatenates the raw text of every segment, discarding the
+// quoting/expansion boundaries between them.
+func (w Word) String() string {
+	var size int
+	for _, s := range w.Segments {
+		size += len(s.Value)
+	}
+	buf := make([]byte, 0, size)
+	for _, s := range w.Segments {
+		buf = append(buf, s.Value...)
+	}
+	return string(buf)
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// matchBracket returns the index just past the rune that closes the
+// bracket pair opened at rs[i], counting nested occurrences of open so
+// that e.g. $((1+(2-1))) closes at the right place. If the pair is
+// never closed, it returns len(rs).
+func matchBracket(rs []rune, i int, open, close rune) int {
+	depth := 0
+	for j := i; j < len(rs); j++ {
+		switch rs[j] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(rs)
+}
+
+// splitWordSegments breaks the literal text of a Word-kind token into
+// Segments, pulling out $var, ${...}, $(...), $((...)) and {...} that
+// the Scanner leaves embedded in plain word text (it only special-cases
+// those forms when they open a token, not when they appear mid-word).
+func splitWordSegments(s string) []Segment {
+	var (
+		segs []Segment
+		lit  []byte
+		rs   = []rune(s)
+	)
+	flush := func() {
+		if len(lit) > 0 {
+			segs = append(segs, Segment{Kind: Literal, Value: string(lit)})
+			lit = lit[:0]
+		}
+	}
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case r == '$' && i+1 < len(rs) && rs[i+1] == '(':
+			flush()
+			kind, openAt := CommandSubst, i+1
+			if i+2 < len(rs) && rs[i+2] == '(' {
+				kind = Arithmetic
+			}
+			end := matchBracket(rs, openAt, '(', ')')
+			segs = append(segs, Segment{Kind: kind, Value: string(rs[i:end])})
+			i = end
+		case r == '$' && i+1 < len(rs) && rs[i+1] == '{':
+			flush()
+			end := matchBracket(rs, i+1, '{', '}')
+			segs = append(segs, Segment{Kind: Parameter, Value: string(rs[i:end])})
+			i = end
+		case r == '$' && i+1 < len(rs) && isNameStart(rs[i+1]):
+			flush()
+			j := i + 1
+			for j < len(rs) && isNameRune(rs[j]) {
+				j++
+			}
+			segs = append(segs, Segment{Kind: Variable, Value: string(rs[i:j])})
+			i = j
+		case r == '{':
+			flush()
+			end := matchBracket(rs, i, '{', '}')
+			segs = append(segs, Segment{Kind: Brace, Value: string(rs[i:end])})
+			i = end
+		default:
+			var buf [utf8.UTFMax]byte
+			n := utf8.EncodeRune(buf[:], r)
+			lit = append(lit, buf[:n]...)
+			i++
+		}
+	}
+	flush()
+	return segs
+}
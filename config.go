@@ -0,0 +1,129 @@
+package shlex
+
+import (
+	"errors"
+	"io"
+)
+
+// Config governs the quoting and comment rules a Scanner applies. The
+// zero value does not reproduce NewScanner's defaults on its own; use
+// NewScannerConfig/SplitWithConfig to opt in.
+type Config struct {
+	// PosixQuoting enables POSIX-correct escaping: single quotes take
+	// every byte verbatim (no escapes at all), and inside double
+	// quotes a backslash only escapes $, \, `, " and newline. When
+	// false, a backslash escapes the closing quote of either kind and
+	// is otherwise kept as-is, matching the historical behavior.
+	PosixQuoting bool
+	// KeepQuotes keeps the surrounding quote characters in the token's
+	// Literal instead of stripping them.
+	KeepQuotes bool
+	// KeepBackslashEscapes keeps the backslash of a recognized escape
+	// in the output alongside the escaped character, instead of
+	// consuming it. Only meaningful together with PosixQuoting.
+	KeepBackslashEscapes bool
+	// Comments enables '#' as a comment marker. When false, '#' is
+	// just another word character.
+	Comments bool
+}
+
+// SplitWithConfig behaves like Split, except that conf governs quoting
+// and comment handling instead of the historical defaults.
+func SplitWithConfig(r io.Reader, conf Config) ([]string, error) {
+	sc := NewScannerConfig(r, conf)
+	var str []string
+	for {
+		tok, err := sc.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return str, err
+		}
+		if tok.Kind == Newline {
+			continue
+		}
+		str = append(str, tok.Literal)
+	}
+	return str, nil
+}
+
+func readQuoteConfig(str runeWriter, rs io.RuneScanner, quote rune, conf Config) error {
+	if conf.KeepQuotes {
+		str.WriteRune(quote)
+	}
+	switch {
+	case isSingle(quote) && conf.PosixQuoting:
+		if err := readSingleQuotePosix(str, rs, quote); err != nil {
+			return err
+		}
+	case isDouble(quote) && conf.PosixQuoting:
+		if err := readDoubleQuotePosix(str, rs, quote, conf.KeepBackslashEscapes); err != nil {
+			return err
+		}
+	default:
+		if err := readQuote(str, rs, quote); err != nil {
+			return err
+		}
+	}
+	if conf.KeepQuotes {
+		str.WriteRune(quote)
+	}
+	return nil
+}
+
+// readSingleQuotePosix copies every rune verbatim until the matching
+// quote: POSIX single quotes know no escapes.
+func readSingleQuotePosix(str runeWriter, rs io.RuneScanner, quote rune) error {
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if r == quote {
+			return nil
+		}
+		str.WriteRune(r)
+	}
+}
+
+// readDoubleQuotePosix implements the POSIX double-quote escaping
+// rule: a backslash only escapes $, \, `, " and newline; anywhere else
+// it is kept literally along with the character after it.
+func readDoubleQuotePosix(str runeWriter, rs io.RuneScanner, quote rune, keepBackslash bool) error {
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if r == quote {
+			return nil
+		}
+		if r != backslash {
+			str.WriteRune(r)
+			continue
+		}
+		n, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if !isDoubleEscapable(n) {
+			str.WriteRune(backslash)
+			str.WriteRune(n)
+			continue
+		}
+		if keepBackslash {
+			str.WriteRune(backslash)
+		}
+		str.WriteRune(n)
+	}
+}
+
+func isDoubleEscapable(r rune) bool {
+	switch r {
+	case dollar, backslash, '`', dquote, nl:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,44 @@
+package shlex
+
+import "fmt"
+
+// ErrorHandler is called for every error the Scanner recovers from. Pos
+// is the position where the offending construct started, and msg
+// describes the problem (e.g. "unterminated double-quoted string
+// starting at 3:17").
+type ErrorHandler func(pos Position, msg string)
+
+// Error describes a scanning failure together with the position it was
+// found at.
+type Error struct {
+	Pos Position
+	Msg string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func describeKind(k Kind) string {
+	switch k {
+	case Brace:
+		return "brace"
+	case Group:
+		return "group"
+	case CommandSubst:
+		return "command substitution"
+	case Arithmetic:
+		return "arithmetic expression"
+	case SingleQuoted:
+		return "single-quoted string"
+	case DoubleQuoted:
+		return "double-quoted string"
+	default:
+		return "construct"
+	}
+}
@@ -0,0 +1,61 @@
+package shlex_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/midbel/shlex"
+)
+
+func TestScannerUnterminatedWithoutHandler(t *testing.T) {
+	sc := shlex.NewScanner(strings.NewReader("echo 'unterminated"))
+
+	if _, err := sc.Next(); err != nil {
+		t.Fatalf("echo: unexpected error! %s", err)
+	}
+	_, err := sc.Next()
+	var serr *shlex.Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *shlex.Error, got %T (%v)", err, err)
+	}
+	if serr.Pos.Line != 1 || serr.Pos.Column != 6 {
+		t.Fatalf("position mismatched! got %s, want 1:6", serr.Pos)
+	}
+	if !errors.Is(serr, shlex.ErrInvalid) {
+		t.Fatalf("expected Unwrap to expose ErrInvalid, got %v", serr.Err)
+	}
+}
+
+func TestScannerUnterminatedWithHandlerRecovers(t *testing.T) {
+	var got []string
+	handler := func(pos shlex.Position, msg string) {
+		got = append(got, msg)
+	}
+	sc := shlex.NewScanner(strings.NewReader("echo 'unterminated"))
+	sc.SetErrorHandler(handler)
+
+	var kinds []shlex.Kind
+	for {
+		tok, err := sc.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatalf("unexpected error! %s", err)
+		}
+		kinds = append(kinds, tok.Kind)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected exactly one recovered error, got %v", got)
+	}
+	if !strings.Contains(got[0], "unterminated single-quoted string starting at 1:6") {
+		t.Fatalf("unexpected message: %q", got[0])
+	}
+	// recovery hands back the partial token and lets the stream end
+	// cleanly via io.EOF instead of aborting with a hard error.
+	if want := []shlex.Kind{shlex.Word, shlex.SingleQuoted}; len(kinds) != len(want) || kinds[0] != want[0] || kinds[1] != want[1] {
+		t.Fatalf("kinds mismatched! got %v, want %v", kinds, want)
+	}
+}
@@ -0,0 +1,249 @@
+package shlex
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ExpandOptions configures the optional post-processing pass Expand
+// performs on top of a scanned token list.
+type ExpandOptions struct {
+	// LookupUser resolves a leading ~ (called with "") or ~user
+	// (called with "user") to a home directory. Tilde expansion is
+	// skipped entirely when LookupUser is nil.
+	LookupUser func(name string) (string, error)
+}
+
+var (
+	numericRangePattern = regexp.MustCompile(`^(-?[0-9]+)\.\.(-?[0-9]+)(?:\.\.(-?[0-9]+))?$`)
+	alphaRangePattern   = regexp.MustCompile(`^([A-Za-z])\.\.([A-Za-z])(?:\.\.(-?[0-9]+))?$`)
+)
+
+// Expand rewrites tokens, performing brace expansion ({a,b,c} and
+// ranges like {1..10}, {01..10}, {a..e}, {1..10..2}) and, when
+// opts.LookupUser is set, leading tilde expansion. It matches bash
+// semantics closely enough for tooling: escaped braces (\{) and
+// unmatched or empty braces ({}) are left untouched.
+func Expand(tokens []string, opts ExpandOptions) ([]string, error) {
+	var out []string
+	for _, tok := range tokens {
+		for _, w := range expandBraces(tok) {
+			if opts.LookupUser != nil && strings.HasPrefix(w, "~") {
+				expanded, err := expandTilde(w, opts.LookupUser)
+				if err != nil {
+					return out, err
+				}
+				w = expanded
+			}
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func expandTilde(w string, lookup func(string) (string, error)) (string, error) {
+	rest := w[1:]
+	name, tail := rest, ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		name, tail = rest[:i], rest[i:]
+	}
+	home, err := lookup(name)
+	if err != nil {
+		return w, err
+	}
+	return home + tail, nil
+}
+
+func expandBraces(s string) []string {
+	start := findBrace(s)
+	if start < 0 {
+		return []string{s}
+	}
+	end := matchBrace(s, start)
+	if end < 0 {
+		return []string{s}
+	}
+	prefix, content, suffix := s[:start], s[start+1:end], s[end+1:]
+
+	alts, ok := braceAlternatives(content)
+	if !ok {
+		var out []string
+		for _, sfx := range expandBraces(suffix) {
+			out = append(out, prefix+"{"+content+"}"+sfx)
+		}
+		return out
+	}
+
+	suffixes := expandBraces(suffix)
+	var out []string
+	for _, alt := range alts {
+		for _, expAlt := range expandBraces(alt) {
+			for _, sfx := range suffixes {
+				out = append(out, prefix+expAlt+sfx)
+			}
+		}
+	}
+	return out
+}
+
+// findBrace returns the index of the first unescaped '{' in s, or -1.
+func findBrace(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == backslash && i+1 < len(s) && (s[i+1] == lcurly || s[i+1] == rcurly) {
+			i++
+			continue
+		}
+		if s[i] == lcurly {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchBrace returns the index of the '}' matching the '{' at s[start],
+// respecting nesting and escaped braces, or -1 if there is none.
+func matchBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		if s[i] == backslash && i+1 < len(s) && (s[i+1] == lcurly || s[i+1] == rcurly) {
+			i++
+			continue
+		}
+		switch s[i] {
+		case lcurly:
+			depth++
+		case rcurly:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// braceAlternatives reports the comma-separated or range alternatives a
+// brace's content expands to. ok is false for empty content or content
+// that is neither a range nor a comma list, meaning the brace should be
+// left alone.
+func braceAlternatives(content string) (alts []string, ok bool) {
+	if content == "" {
+		return nil, false
+	}
+	if m := numericRangePattern.FindStringSubmatch(content); m != nil {
+		return numericRange(m[1], m[2], m[3]), true
+	}
+	if m := alphaRangePattern.FindStringSubmatch(content); m != nil {
+		return alphaRange(rune(m[1][0]), rune(m[2][0]), m[3]), true
+	}
+	parts := splitTopLevel(content, ',')
+	if len(parts) < 2 {
+		return nil, false
+	}
+	return parts, true
+}
+
+func splitTopLevel(s string, sep byte) []string {
+	var (
+		parts []string
+		depth int
+		last  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == backslash && i+1 < len(s) && (s[i+1] == lcurly || s[i+1] == rcurly):
+			i++
+		case s[i] == lcurly:
+			depth++
+		case s[i] == rcurly:
+			depth--
+		case s[i] == sep && depth == 0:
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+func numericRange(startStr, endStr, stepStr string) []string {
+	start, _ := strconv.Atoi(startStr)
+	end, _ := strconv.Atoi(endStr)
+	step := 1
+	if stepStr != "" {
+		if n, _ := strconv.Atoi(stepStr); n != 0 {
+			step = abs(n)
+		}
+	}
+	width := 0
+	if hasLeadingZero(startStr) || hasLeadingZero(endStr) {
+		width = max(len(trimSign(startStr)), len(trimSign(endStr)))
+		if strings.HasPrefix(startStr, "-") || strings.HasPrefix(endStr, "-") {
+			// Reserve a column for the sign so every member lines up,
+			// matching bash: {-01..03} is -01 000 001 002 003, not
+			// -1 00 01 02 03.
+			width++
+		}
+	}
+	var out []string
+	if start <= end {
+		for v := start; v <= end; v += step {
+			out = append(out, formatRangeNum(v, width))
+		}
+	} else {
+		for v := start; v >= end; v -= step {
+			out = append(out, formatRangeNum(v, width))
+		}
+	}
+	return out
+}
+
+func alphaRange(start, end rune, stepStr string) []string {
+	step := 1
+	if stepStr != "" {
+		if n, _ := strconv.Atoi(stepStr); n != 0 {
+			step = abs(n)
+		}
+	}
+	var out []string
+	if start <= end {
+		for c := int(start); c <= int(end); c += step {
+			out = append(out, string(rune(c)))
+		}
+	} else {
+		for c := int(start); c >= int(end); c -= step {
+			out = append(out, string(rune(c)))
+		}
+	}
+	return out
+}
+
+func formatRangeNum(v, width int) string {
+	if width == 0 {
+		return strconv.Itoa(v)
+	}
+	return fmt.Sprintf("%0*d", width, v)
+}
+
+func hasLeadingZero(s string) bool {
+	return len(trimSign(s)) > 1 && trimSign(s)[0] == '0'
+}
+
+func trimSign(s string) string {
+	return strings.TrimPrefix(s, "-")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
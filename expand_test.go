@@ -0,0 +1,80 @@
+package shlex_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/shlex"
+)
+
+func TestSplitExpandBraceCommaList(t *testing.T) {
+	got, err := shlex.SplitExpand(strings.NewReader("echo a{b,c}d"), shlex.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", "abd", "acd"}
+	assertWords(t, got, want)
+}
+
+func TestSplitExpandAdjacentBracesCrossProduct(t *testing.T) {
+	got, err := shlex.SplitExpand(strings.NewReader("echo {1..3}{x,y}"), shlex.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", "1x", "1y", "2x", "2y", "3x", "3y"}
+	assertWords(t, got, want)
+}
+
+func TestSplitExpandEmptyBraceLeftAlone(t *testing.T) {
+	got, err := shlex.SplitExpand(strings.NewReader("echo {}"), shlex.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", "{}"}
+	assertWords(t, got, want)
+}
+
+func TestSplitExpandTilde(t *testing.T) {
+	lookup := func(name string) (string, error) {
+		if name == "" {
+			return "/home/me", nil
+		}
+		return "/home/" + name, nil
+	}
+	got, err := shlex.SplitExpand(strings.NewReader("echo ~/bin ~bob/bin"), shlex.ExpandOptions{LookupUser: lookup})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", "/home/me/bin", "/home/bob/bin"}
+	assertWords(t, got, want)
+}
+
+func TestExpandNumericRangeZeroPadded(t *testing.T) {
+	got, err := shlex.Expand([]string{"{01..03}"}, shlex.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"01", "02", "03"}
+	assertWords(t, got, want)
+}
+
+func TestExpandNumericRangeNegativeZeroPaddedReservesSignColumn(t *testing.T) {
+	got, err := shlex.Expand([]string{"{-01..03}"}, shlex.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"-01", "000", "001", "002", "003"}
+	assertWords(t, got, want)
+}
+
+func assertWords(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("length mismatched! got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("word %d mismatched! got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package shlex
+
+import (
+	"regexp"
+	"strings"
+)
+
+var unsafeWord = regexp.MustCompile(`[^\w@%+=:,./-]`)
+
+// Quote returns a shell-safe single-quoted version of word, analogous
+// to Python's shlex.quote. Words that need no quoting are returned
+// unchanged.
+func Quote(word string) string {
+	if word == "" {
+		return "''"
+	}
+	if !unsafeWord.MatchString(word) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'"'"'`) + "'"
+}
+
+// Join quotes every word with Quote and joins the result with spaces,
+// producing a string that a POSIX shell splits back into words. Split
+// does not glue adjacent tokens together, so a word containing a single
+// quote comes back from Split as several tokens.
+func Join(words []string) string {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = Quote(w)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,110 @@
+package shlex_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/shlex"
+)
+
+var quoteList = []struct {
+	Input string
+	Want  string
+}{
+	{Input: "", Want: "''"},
+	{Input: "foo", Want: "foo"},
+	{Input: "foo/bar.go", Want: "foo/bar.go"},
+	{Input: "foo bar", Want: "'foo bar'"},
+	{Input: "it's", Want: `'it'"'"'s'`},
+}
+
+func TestQuote(t *testing.T) {
+	for _, in := range quoteList {
+		got := shlex.Quote(in.Input)
+		if got != in.Want {
+			t.Errorf("%q: got %q, want %q", in.Input, got, in.Want)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	// Words containing a quote character are deliberately excluded here:
+	// Quote splices quoted and unquoted segments together (the classic
+	// 'it'"'"'s' trick), and shlex.Split, unlike the ast package, does
+	// not glue adjacent tokens back into one word.
+	words := []string{"foo", "foo bar", "", "$HOME"}
+	joined := shlex.Join(words)
+	got, err := shlex.SplitString(joined)
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if len(got) != len(words) {
+		t.Fatalf("round-trip length mismatched! got %q, want %q", got, words)
+	}
+	for i := range words {
+		if got[i] != words[i] {
+			t.Errorf("word %d mismatched! got %q, want %q", i, got[i], words[i])
+		}
+	}
+}
+
+func TestSplitWithConfigPosixDoubleQuoteEscapes(t *testing.T) {
+	got, err := shlex.SplitWithConfig(strings.NewReader(`echo "a\$b\c"`), shlex.Config{PosixQuoting: true})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", `a$b\c`}
+	if len(got) != len(want) || got[1] != want[1] {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitWithConfigNonPosixKeepsBothBackslashes(t *testing.T) {
+	got, err := shlex.SplitWithConfig(strings.NewReader(`echo "a\$b\c"`), shlex.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", `a\$b\c`}
+	if len(got) != len(want) || got[1] != want[1] {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitWithConfigPosixSingleQuoteHasNoEscapes(t *testing.T) {
+	got, err := shlex.SplitWithConfig(strings.NewReader(`echo 'a\$b'`), shlex.Config{PosixQuoting: true})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", `a\$b`}
+	if len(got) != len(want) || got[1] != want[1] {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitWithConfigKeepQuotes(t *testing.T) {
+	got, err := shlex.SplitWithConfig(strings.NewReader(`echo "foo bar"`), shlex.Config{KeepQuotes: true})
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	want := []string{"echo", `"foo bar"`}
+	if len(got) != len(want) || got[1] != want[1] {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestScannerConfigCommentsDisabled(t *testing.T) {
+	sc := shlex.NewScannerConfig(strings.NewReader("echo foo #bar"), shlex.Config{})
+	var kinds []shlex.Kind
+	for {
+		tok, err := sc.Next()
+		if err != nil {
+			break
+		}
+		kinds = append(kinds, tok.Kind)
+	}
+	for _, k := range kinds {
+		if k == shlex.Comment {
+			t.Fatalf("expected no Comment token with Comments disabled, got kinds %v", kinds)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package shlex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Kind identifies the lexical class of a Token.
+type Kind int
+
+const (
+	Word Kind = iota
+	Operator
+	SingleQuoted
+	DoubleQuoted
+	CommandSubst
+	Arithmetic
+	Brace
+	Group
+	Comment
+	Newline
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Word:
+		return "word"
+	case Operator:
+		return "operator"
+	case SingleQuoted:
+		return "single-quoted"
+	case DoubleQuoted:
+		return "double-quoted"
+	case CommandSubst:
+		return "command-subst"
+	case Arithmetic:
+		return "arithmetic"
+	case Brace:
+		return "brace"
+	case Group:
+		return "group"
+	case Comment:
+		return "comment"
+	case Newline:
+		return "newline"
+	default:
+		return "unknown"
+	}
+}
+
+// Position locates a Token inside the scanned stream. Offset and Column
+// count runes, not bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Token is a single lexical unit produced by a Scanner.
+type Token struct {
+	Kind    Kind
+	Literal string
+	Pos     Position
+}
+
+// Scanner reads a shell-like stream and emits one Token at a time,
+// tracking line and column as it goes. It is the engine behind Split and
+// SplitString, but it can also be driven directly by callers that want
+// to consume large scripts without materializing the full token slice.
+type Scanner struct {
+	rs      *bufio.Reader
+	pos     Position
+	prev    Position
+	handler ErrorHandler
+	conf    *Config
+}
+
+// NewScanner returns a Scanner reading from r, using the historical
+// quoting/comment rules (backslash keeps its quote character, quotes
+// are stripped, # always starts a comment). Use NewScannerConfig to
+// change any of that.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		rs:  bufio.NewReader(r),
+		pos: Position{Line: 1, Column: 1},
+	}
+}
+
+// NewScannerConfig returns a Scanner reading from r whose quoting and
+// comment handling is governed by conf.
+func NewScannerConfig(r io.Reader, conf Config) *Scanner {
+	s := NewScanner(r)
+	s.conf = &conf
+	return s
+}
+
+func (s *Scanner) commentsEnabled() bool {
+	return s.conf == nil || s.conf.Comments
+}
+
+// SetErrorHandler registers h to be called for every error the Scanner
+// recovers from. When no handler is set, Next stops and returns the
+// first error it meets, mirroring the pre-Scanner behavior of Split.
+func (s *Scanner) SetErrorHandler(h ErrorHandler) {
+	s.handler = h
+}
+
+// ReadRune implements io.RuneScanner, updating the current Position as
+// runes are consumed.
+func (s *Scanner) ReadRune() (rune, int, error) {
+	s.prev = s.pos
+	r, n, err := s.rs.ReadRune()
+	if err != nil {
+		return r, n, err
+	}
+	if r == nl {
+		s.pos.Line++
+		s.pos.Column = 1
+	} else {
+		s.pos.Column++
+	}
+	s.pos.Offset++
+	return r, n, nil
+}
+
+// UnreadRune implements io.RuneScanner. Only the last rune read can be
+// unread, matching the guarantee of the underlying bufio.Reader.
+func (s *Scanner) UnreadRune() error {
+	if err := s.rs.UnreadRune(); err != nil {
+		return err
+	}
+	s.pos = s.prev
+	return nil
+}
+
+// Next returns the next Token in the stream, or an error if reading the
+// underlying reader fails. It returns io.EOF once the stream is
+// exhausted.
+//
+// When an unterminated construct (a quote, brace, group, command
+// substitution or arithmetic expression left open at EOF) is found,
+// Next reports the position it started at. If an ErrorHandler was
+// registered via SetErrorHandler, it is invoked with that position and
+// a description of the problem, and Next recovers by returning the
+// partial token it managed to read with a nil error, so scanning of the
+// rest of the stream can continue. Without a handler, Next stops and
+// returns the error immediately, matching the original behavior of
+// Split.
+func (s *Scanner) Next() (Token, error) {
+	var buf bytes.Buffer
+	for {
+		pos := s.pos
+		r, _, err := s.ReadRune()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return Token{}, io.EOF
+			}
+			return Token{}, err
+		}
+		switch {
+		case isBlank(r):
+			readBlank(s)
+			continue
+		case isNL(r):
+			return Token{Kind: Newline, Literal: string(r), Pos: pos}, nil
+		case isBrace(r):
+			err = readBrace(&buf, s)
+			return s.token(Brace, buf, pos, err)
+		case isParen(r):
+			err = readGroup(&buf, s)
+			return s.token(Group, buf, pos, err)
+		case isDollar(r):
+			kind, err := readDollar(&buf, s)
+			return s.token(kind, buf, pos, err)
+		case isQuote(r):
+			if s.conf != nil {
+				err = readQuoteConfig(&buf, s, r, *s.conf)
+			} else {
+				err = readQuote(&buf, s, r)
+			}
+			return s.token(quoteKind(r), buf, pos, err)
+		case isDelimiter(r):
+			readDelimiter(&buf, s, r)
+			return s.token(Operator, buf, pos, nil)
+		case isRedir(r):
+			readRedir(&buf, s, r)
+			return s.token(Operator, buf, pos, nil)
+		case s.commentsEnabled() && isComment(r):
+			readComment(&buf, s)
+			return s.token(Comment, buf, pos, nil)
+		default:
+			readWord(&buf, s, r)
+			return s.token(Word, buf, pos, nil)
+		}
+	}
+}
+
+func (s *Scanner) token(kind Kind, buf bytes.Buffer, pos Position, err error) (Token, error) {
+	if err == nil {
+		return Token{Kind: kind, Literal: buf.String(), Pos: pos}, nil
+	}
+	msg := fmt.Sprintf("unterminated %s starting at %s", describeKind(kind), pos)
+	if s.handler == nil {
+		return Token{}, &Error{Pos: pos, Msg: msg, Err: err}
+	}
+	s.handler(pos, msg)
+	return Token{Kind: kind, Literal: buf.String(), Pos: pos}, nil
+}
+
+// isWordKind reports whether k is one of the token kinds that make up a
+// shell word, as opposed to an Operator, Comment or Newline. Two
+// word-kind tokens with nothing between them (same rune offset) belong
+// to the same word, e.g. the Brace+Brace pair in {1..3}{x,y}.
+func isWordKind(k Kind) bool {
+	switch k {
+	case Word, SingleQuoted, DoubleQuoted, CommandSubst, Arithmetic, Brace:
+		return true
+	default:
+		return false
+	}
+}
+
+func quoteKind(r rune) Kind {
+	if isSingle(r) {
+		return SingleQuoted
+	}
+	return DoubleQuoted
+}
+
+type runeWriter interface {
+	WriteRune(rune) (int, error)
+}
+
+func readComment(str runeWriter, rs io.RuneScanner) error {
+	str.WriteRune(dash)
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil {
+			break
+		}
+		str.WriteRune(r)
+	}
+	return nil
+}
+
+func readDollar(str runeWriter, rs io.RuneScanner) (Kind, error) {
+	if r, _, _ := rs.ReadRune(); r != lparen {
+		rs.UnreadRune()
+		readWord(str, rs, dollar)
+		return Word, nil
+	}
+	if r, _, _ := rs.ReadRune(); r == lparen {
+		return Arithmetic, readArithmetic(str, rs)
+	}
+	rs.UnreadRune()
+	return CommandSubst, readSubstitution(str, rs)
+}
+
+func readSubstitution(str runeWriter, rs io.RuneScanner) error {
+	str.WriteRune(dollar)
+	str.WriteRune(lparen)
+
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if r == dollar {
+			if _, err = readDollar(str, rs); err != nil {
+				return err
+			}
+			continue
+		}
+		if r == rparen {
+			break
+		}
+		str.WriteRune(r)
+	}
+	str.WriteRune(rparen)
+	return nil
+}
+
+func readArithmetic(str runeWriter, rs io.RuneScanner) error {
+	str.WriteRune(dollar)
+	str.WriteRune(lparen)
+	str.WriteRune(lparen)
+
+	var prev rune
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if r == dollar {
+			if _, err = readDollar(str, rs); err != nil {
+				return err
+			}
+			continue
+		}
+		if r == rparen && prev == rparen {
+			break
+		}
+		if r == lparen {
+			if err = readGroup(str, rs); err != nil {
+				return err
+			}
+			continue
+		}
+		prev = r
+		str.WriteRune(r)
+	}
+	str.WriteRune(rparen)
+	return nil
+}
+
+func readBrace(str runeWriter, rs io.RuneScanner) error {
+	str.WriteRune(lcurly)
+	for {
+		c, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if c == rcurly {
+			break
+		}
+		if c == lcurly {
+			if err = readBrace(str, rs); err != nil {
+				return err
+			}
+			continue
+		}
+		str.WriteRune(c)
+	}
+	str.WriteRune(rcurly)
+	return nil
+}
+
+func readGroup(str runeWriter, rs io.RuneScanner) error {
+	str.WriteRune(lparen)
+	for {
+		c, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if c == rparen {
+			break
+		}
+		if c == lparen {
+			if err = readGroup(str, rs); err != nil {
+				return err
+			}
+			continue
+		}
+		str.WriteRune(c)
+	}
+	str.WriteRune(rparen)
+	return nil
+}
+
+func readWord(str runeWriter, rs io.RuneScanner, r rune) {
+	str.WriteRune(r)
+	for {
+		r, _, err := rs.ReadRune()
+		if eow(r) || err != nil {
+			if err == nil {
+				rs.UnreadRune()
+			}
+			break
+		}
+		str.WriteRune(r)
+	}
+}
+
+func readDelimiter(str runeWriter, rs io.RuneScanner, r rune) {
+	str.WriteRune(r)
+	for {
+		r, _, err := rs.ReadRune()
+		if !isDelimiter(r) || err != nil {
+			break
+		}
+		str.WriteRune(r)
+	}
+	rs.UnreadRune()
+}
+
+// readRedir reads one of the <, <<, <&, >, >>, >& redirection operators
+// starting at r, whatever follows (blank or not), so a redirection is
+// never silently swallowed as plain word text.
+func readRedir(str runeWriter, rs io.RuneScanner, r rune) {
+	str.WriteRune(r)
+	n, _, err := rs.ReadRune()
+	if err != nil {
+		return
+	}
+	if n == r || n == ampersand {
+		str.WriteRune(n)
+		return
+	}
+	rs.UnreadRune()
+}
+
+func readQuote(str runeWriter, rs io.RuneScanner, quote rune) error {
+	var prev rune
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil {
+			return ErrInvalid
+		}
+		if r == quote && prev != backslash {
+			break
+		}
+		prev = r
+		str.WriteRune(r)
+	}
+	return nil
+}
+
+// readBlank consumes a run of horizontal whitespace, stopping before a
+// line terminator so Next still sees and emits it as a Newline token.
+func readBlank(rs io.RuneScanner) {
+	for {
+		r, _, err := rs.ReadRune()
+		if err != nil || !isBlank(r) {
+			break
+		}
+	}
+	rs.UnreadRune()
+}
+
+const (
+	ampersand = '&'
+	pipe      = '|'
+	semicolon = ';'
+	space     = ' '
+	tab       = '\t'
+	squote    = '\''
+	dquote    = '"'
+	backslash = '\\'
+	nl        = '\n'
+	cr        = '\r'
+	dollar    = '$'
+	lparen    = '('
+	rparen    = ')'
+	lcurly    = '{'
+	rcurly    = '}'
+	dash      = '#'
+	equal     = '='
+	minus     = '-'
+	lt        = '<'
+	gt        = '>'
+)
+
+func eow(r rune) bool {
+	return isDelimiter(r) || isQuote(r) || isBlank(r) || isNL(r) || r == equal || isRedir(r)
+}
+
+func isParen(r rune) bool {
+	return r == lparen
+}
+
+func isBrace(r rune) bool {
+	return r == lcurly
+}
+
+func isComment(r rune) bool {
+	return r == dash
+}
+
+func isDollar(r rune) bool {
+	return r == dollar
+}
+
+func isDelimiter(r rune) bool {
+	return r == ampersand || r == pipe || r == semicolon
+}
+
+func isRedir(r rune) bool {
+	return r == lt || r == gt
+}
+
+func isBlank(r rune) bool {
+	return r == space || r == tab
+}
+
+func isDouble(r rune) bool {
+	return r == dquote
+}
+
+func isSingle(r rune) bool {
+	return r == squote
+}
+
+func isQuote(r rune) bool {
+	return isDouble(r) || isSingle(r)
+}
+
+func isNL(r rune) bool {
+	return r == cr || r == nl
+}
@@ -0,0 +1,87 @@
+package shlex_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/shlex"
+)
+
+func TestScannerPositionCountsRunes(t *testing.T) {
+	sc := shlex.NewScanner(strings.NewReader("café bar"))
+
+	first, err := sc.Next()
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if first.Literal != "café" {
+		t.Fatalf("literal mismatched! got %q, want %q", first.Literal, "café")
+	}
+
+	second, err := sc.Next()
+	if err != nil {
+		t.Fatalf("unexpected error! %s", err)
+	}
+	if second.Literal != "bar" {
+		t.Fatalf("literal mismatched! got %q, want %q", second.Literal, "bar")
+	}
+	if want := 5; second.Pos.Offset != want {
+		t.Fatalf("offset not rune-accurate! got %d, want %d", second.Pos.Offset, want)
+	}
+	if want := 6; second.Pos.Column != want {
+		t.Fatalf("column not rune-accurate! got %d, want %d", second.Pos.Column, want)
+	}
+}
+
+func TestScannerRedirOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"cmd 2>&1", []string{"cmd", "2", ">&", "1"}},
+		{"cat<file", []string{"cat", "<", "file"}},
+		{"cmd << EOF", []string{"cmd", "<<", "EOF"}},
+		{"cmd >> out", []string{"cmd", ">>", "out"}},
+	}
+	for _, tt := range tests {
+		sc := shlex.NewScanner(strings.NewReader(tt.input))
+		var got []string
+		for {
+			tok, err := sc.Next()
+			if err != nil {
+				break
+			}
+			got = append(got, tok.Literal)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %q, want %q", tt.input, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: token %d mismatched! got %q, want %q", tt.input, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestScannerNewlineAfterTrailingBlank(t *testing.T) {
+	sc := shlex.NewScanner(strings.NewReader("a \nb"))
+
+	var kinds []shlex.Kind
+	for {
+		tok, err := sc.Next()
+		if err != nil {
+			break
+		}
+		kinds = append(kinds, tok.Kind)
+	}
+	want := []shlex.Kind{shlex.Word, shlex.Newline, shlex.Word}
+	if len(kinds) != len(want) {
+		t.Fatalf("kind count mismatched! got %v, want %v", kinds, want)
+	}
+	for i := range kinds {
+		if kinds[i] != want[i] {
+			t.Fatalf("kind mismatched at %d! got %s, want %s", i, kinds[i], want[i])
+		}
+	}
+}